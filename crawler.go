@@ -0,0 +1,307 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// resultsBufferSize sizes the Results() channel so a burst of fetches
+// can complete without blocking on a slow consumer.
+const resultsBufferSize = 64
+
+// storePollInterval is how long an idle worker waits before asking an
+// empty Store for more work again.
+const storePollInterval = 10 * time.Millisecond
+
+// defaultParentCap bounds Crawler.parentOf, in the same spirit as
+// MemoryStore's DefaultExactCap, so a crawl with a huge frontier doesn't
+// grow that map without limit.
+const defaultParentCap = DefaultExactCap
+
+// Crawler owns the per-host scheduling state for a single crawl, and
+// drives its frontier and visited set through a Store (unlike the old
+// package-level globals, so two Crawlers, or two concurrent calls to
+// Run, never interfere with each other).
+type Crawler struct {
+	Options Options
+	fetcher Fetcher
+	robots  *RobotsPolicy
+	store   Store
+
+	hostMu   sync.Mutex
+	hostNext map[string]time.Time
+
+	// parentOf is a best-effort, in-process-only record of which URL
+	// discovered each frontier entry, used to fill PageResult.ParentURL.
+	// It isn't persisted, so entries hydrated from a prior run's Store
+	// report an empty ParentURL. It stops growing past parentCap, so
+	// crawls with huge frontiers don't hold one entry per URL in memory
+	// forever; URLs enqueued after the cap simply report an empty
+	// ParentURL.
+	parentMu  sync.Mutex
+	parentOf  map[string]string
+	parentCap int
+
+	processed int64
+
+	results chan PageResult
+}
+
+// NewCrawler builds a Crawler ready to run a single crawl described by
+// opts. Passing opts.Store from a previous crawl resumes it.
+func NewCrawler(opts Options) *Crawler {
+	fetcher := opts.fetcher()
+	return &Crawler{
+		Options:   opts,
+		fetcher:   fetcher,
+		robots:    opts.robotsPolicy(fetcher),
+		store:     opts.store(),
+		hostNext:  make(map[string]time.Time),
+		parentOf:  make(map[string]string),
+		parentCap: defaultParentCap,
+		results:   make(chan PageResult, resultsBufferSize),
+	}
+}
+
+// Results streams a PageResult for every URL the crawl fetches (or
+// fails to fetch), in completion order. It is closed once Crawl
+// returns, so callers typically range over it from a separate
+// goroutine than the one calling Crawl.
+func (c *Crawler) Results() <-chan PageResult {
+	return c.results
+}
+
+// Crawl runs the crawl with Options.Concurrency worker goroutines
+// pulling from the Store's frontier, until it drains, ctx is done, or
+// the link limit is reached.
+func (c *Crawler) Crawl(ctx context.Context) error {
+	concurrency := c.Options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	seed, err := normalizeURL(nil, c.Options.URL)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var pending int64
+
+	enqueue := func(url, parent string, depth int) {
+		if !c.store.TryMark(url) {
+			return
+		}
+		c.setParent(url, parent)
+		atomic.AddInt64(&pending, 1)
+		c.store.Enqueue(url, depth)
+	}
+
+	// Re-prime pending for whatever a prior run already left queued but
+	// undequeued, so this run doesn't exit before draining it.
+	// Drain it fully before putting it back, since re-enqueueing while
+	// dequeueing from the same FIFO would never find it empty.
+	var leftover []queueEntry
+	for {
+		u, depth, ok := c.store.Dequeue()
+		if !ok {
+			break
+		}
+		leftover = append(leftover, queueEntry{url: u, depth: depth})
+	}
+	for _, e := range leftover {
+		atomic.AddInt64(&pending, 1)
+		c.store.Enqueue(e.url, e.depth)
+	}
+
+	enqueue(seed, "", 0)
+	if c.Options.DiscoverSitemaps {
+		found, err := DiscoverSitemapURLs(ctx, c.robots, c.fetcher, seed)
+		if err != nil {
+			logrus.WithField("seed", seed).Warnf("sitemap discovery failed: %+v", err)
+		}
+		for _, su := range found {
+			nu, err := normalizeURL(nil, su)
+			if err != nil {
+				continue
+			}
+			enqueue(nu, seed, 0)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				u, depth, ok := c.store.Dequeue()
+				if !ok {
+					if atomic.LoadInt64(&pending) == 0 {
+						return
+					}
+					select {
+					case <-time.After(storePollInterval):
+						continue
+					case <-ctx.Done():
+						return
+					}
+				}
+				c.process(ctx, u, depth, enqueue)
+				atomic.AddInt64(&pending, -1)
+			}
+		}()
+	}
+	wg.Wait()
+	close(c.results)
+
+	return errors.WithStack(ctx.Err())
+}
+
+// process fetches a single frontier URL, emits its PageResult, and
+// enqueues the links it discovers.
+func (c *Crawler) process(ctx context.Context, u string, depth int, enqueue func(url, parent string, depth int)) {
+	l := logrus.WithField("url", u).WithField("depth", depth)
+	if err := ctx.Err(); err != nil {
+		return
+	}
+	if c.linksLimitReached() {
+		l.Infof("this is enough links")
+		return
+	}
+	up, err := url.Parse(u)
+	if err != nil {
+		return
+	}
+	allowed, err := c.robots.Allowed(ctx, u)
+	if err != nil {
+		l.Warnf("robots.txt check failed, allowing: %+v", err)
+		allowed = true
+	}
+	if !allowed {
+		l.Infof("disallowed by robots.txt, skipping")
+		return
+	}
+	delay := c.Options.HostDelay
+	if crawlDelay, err := c.robots.CrawlDelay(ctx, u); err == nil && crawlDelay > delay {
+		delay = crawlDelay
+	}
+	if err := c.politeWait(ctx, up.Host, delay); err != nil {
+		return
+	}
+
+	atomic.AddInt64(&c.processed, 1)
+	t1 := time.Now()
+	out, err := parse(ctx, u, c.fetcher, c.Options)
+	result := PageResult{
+		URL:       u,
+		Depth:     depth,
+		ParentURL: c.parent(u),
+		Duration:  time.Since(t1),
+	}
+	if err != nil {
+		result.Err = err
+	} else {
+		result.FinalURL = out.FinalURL
+		result.StatusCode = out.StatusCode
+		result.Links = out.Links
+	}
+	c.emit(ctx, result)
+	if err != nil {
+		return
+	}
+
+	if c.Options.MaxDepth > 0 && depth >= c.Options.MaxDepth {
+		return
+	}
+	for _, href := range out.Links {
+		nu, err := normalizeURL(up, href)
+		if err != nil {
+			l.WithField("href", href).Warnf("skipping unparseable link: %+v", err)
+			continue
+		}
+		enqueue(nu, u, depth+1)
+	}
+}
+
+func (c *Crawler) setParent(url, parent string) {
+	c.parentMu.Lock()
+	defer c.parentMu.Unlock()
+	if len(c.parentOf) >= c.parentCap {
+		return
+	}
+	c.parentOf[url] = parent
+}
+
+func (c *Crawler) parent(url string) string {
+	c.parentMu.Lock()
+	defer c.parentMu.Unlock()
+	return c.parentOf[url]
+}
+
+// emit sends r on c.results, giving up only if ctx is done so a
+// consumer that stops reading can't wedge the crawl forever.
+func (c *Crawler) emit(ctx context.Context, r PageResult) {
+	select {
+	case c.results <- r:
+	case <-ctx.Done():
+	}
+}
+
+func (c *Crawler) linksLimitReached() bool {
+	if c.Options.LinksLimit == 0 {
+		return false
+	}
+	return atomic.LoadInt64(&c.processed) >= int64(c.Options.LinksLimit)
+}
+
+// politeWait blocks until delay has elapsed since the last request to
+// host from any worker, reserving the next slot before returning.
+func (c *Crawler) politeWait(ctx context.Context, host string, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	c.hostMu.Lock()
+	now := time.Now()
+	next := c.hostNext[host]
+	if next.Before(now) {
+		next = now
+	}
+	wait := next.Sub(now)
+	c.hostNext[host] = next.Add(delay)
+	c.hostMu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// normalizeURL resolves href against base (if given), strips its
+// fragment, lowercases its host, and sorts its query parameters, so
+// that equivalent links dedup to the same frontier entry.
+func normalizeURL(base *url.URL, href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	u.Fragment = ""
+	u.Host = strings.ToLower(u.Host)
+	if u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+	return u.String(), nil
+}
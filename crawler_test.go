@@ -0,0 +1,146 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNormalizeURLResolvesRelative(t *testing.T) {
+	base, err := url.Parse("https://example.com/a/b/page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := normalizeURL(base, "foo/bar")
+	if err != nil {
+		t.Fatalf("normalizeURL: %+v", err)
+	}
+	if want := "https://example.com/a/b/foo/bar"; got != want {
+		t.Fatalf("normalizeURL = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeURLStripsFragmentAndLowercasesHost(t *testing.T) {
+	got, err := normalizeURL(nil, "https://EXAMPLE.com/page#section")
+	if err != nil {
+		t.Fatalf("normalizeURL: %+v", err)
+	}
+	if want := "https://example.com/page"; got != want {
+		t.Fatalf("normalizeURL = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeURLSortsQuery(t *testing.T) {
+	got, err := normalizeURL(nil, "https://example.com/page?b=2&a=1")
+	if err != nil {
+		t.Fatalf("normalizeURL: %+v", err)
+	}
+	if want := "https://example.com/page?a=1&b=2"; got != want {
+		t.Fatalf("normalizeURL = %q, want %q", got, want)
+	}
+}
+
+// fakePageFetcher serves canned HTML per URL and counts how many times
+// each URL is fetched, for asserting the worker pool's frontier dedup.
+type fakePageFetcher struct {
+	pages map[string]string
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newFakePageFetcher(pages map[string]string) *fakePageFetcher {
+	return &fakePageFetcher{pages: pages, counts: make(map[string]int)}
+}
+
+func (f *fakePageFetcher) Fetch(ctx context.Context, u string) (*http.Response, []byte, error) {
+	f.mu.Lock()
+	f.counts[u]++
+	f.mu.Unlock()
+	body := f.pages[u]
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+		Request:    &http.Request{URL: mustParseURL(u)},
+	}, []byte(body), nil
+}
+
+func (f *fakePageFetcher) countOf(u string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.counts[u]
+}
+
+func mustParseURL(u string) *url.URL {
+	up, err := url.Parse(u)
+	if err != nil {
+		panic(err)
+	}
+	return up
+}
+
+func TestCrawlDedupesLinksSharedAcrossPages(t *testing.T) {
+	const shared = "https://example.com/shared"
+	fetcher := newFakePageFetcher(map[string]string{
+		"https://example.com/":  `<a href="/a">a</a><a href="/b">b</a>`,
+		"https://example.com/a": `<a href="/shared">shared</a>`,
+		"https://example.com/b": `<a href="/shared">shared</a>`,
+		shared:                  ``,
+	})
+	opts := Options{
+		URL:           "https://example.com/",
+		StatusCodeMin: 200,
+		StatusCodeMax: 299,
+		Concurrency:   4,
+		Fetcher:       fetcher,
+		Robots:        &RobotsPolicy{Disabled: true},
+	}
+	c := NewCrawler(opts)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range c.Results() {
+		}
+	}()
+	if err := c.Crawl(context.Background()); err != nil {
+		t.Fatalf("Crawl: %+v", err)
+	}
+	<-done
+
+	if got := fetcher.countOf(shared); got != 1 {
+		t.Fatalf("shared link fetched %d times, want exactly 1", got)
+	}
+}
+
+func TestCrawlerPoliteWaitEnforcesHostDelay(t *testing.T) {
+	c := NewCrawler(Options{URL: "https://example.com/"})
+	const delay = 30 * time.Millisecond
+	if err := c.politeWait(context.Background(), "example.com", delay); err != nil {
+		t.Fatalf("first politeWait: %+v", err)
+	}
+	start := time.Now()
+	if err := c.politeWait(context.Background(), "example.com", delay); err != nil {
+		t.Fatalf("second politeWait: %+v", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatalf("second politeWait returned after %v, want >= %v", elapsed, delay)
+	}
+}
+
+func TestCrawlerPoliteWaitDoesNotDelayDifferentHosts(t *testing.T) {
+	c := NewCrawler(Options{URL: "https://example.com/"})
+	const delay = time.Hour
+	if err := c.politeWait(context.Background(), "a.example.com", delay); err != nil {
+		t.Fatalf("politeWait a: %+v", err)
+	}
+	start := time.Now()
+	if err := c.politeWait(context.Background(), "b.example.com", delay); err != nil {
+		t.Fatalf("politeWait b: %+v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("politeWait for a different host waited %v, want near-instant", elapsed)
+	}
+}
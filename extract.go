@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Selector names a goquery query and the attribute holding a link on
+// whatever it matches, e.g. {"img", "src"}.
+type Selector struct {
+	Query string
+	Attr  string
+}
+
+// DefaultLinkSelectors covers the elements most pages use to reference
+// other resources. Options.LinkSelectors is appended to this set.
+var DefaultLinkSelectors = []Selector{
+	{Query: "a", Attr: "href"},
+	{Query: "link", Attr: "href"},
+	{Query: "area", Attr: "href"},
+	{Query: "iframe", Attr: "src"},
+	{Query: "img", Attr: "src"},
+}
+
+// extractLinks collects every link matched by DefaultLinkSelectors plus
+// extra, and any meta-refresh target, resolved against up and, when
+// onlySameHost is set, filtered to up's main domain.
+func extractLinks(d *goquery.Document, up *url.URL, onlySameHost bool, extra []Selector) []string {
+	var links []string
+	add := func(href string) {
+		if l, ok := resolveLink(up, href, onlySameHost); ok {
+			links = append(links, l)
+		}
+	}
+	for _, sel := range DefaultLinkSelectors {
+		d.Find(sel.Query).Each(func(i int, s *goquery.Selection) {
+			if href, exists := s.Attr(sel.Attr); exists {
+				add(href)
+			}
+		})
+	}
+	for _, sel := range extra {
+		d.Find(sel.Query).Each(func(i int, s *goquery.Selection) {
+			if href, exists := s.Attr(sel.Attr); exists {
+				add(href)
+			}
+		})
+	}
+	if href, ok := metaRefreshTarget(d); ok {
+		add(href)
+	}
+	return links
+}
+
+// metaRefreshTarget returns the target of <meta http-equiv="refresh"
+// content="N; url=...">, if any.
+func metaRefreshTarget(d *goquery.Document) (string, bool) {
+	var target string
+	var found bool
+	d.Find("meta[http-equiv]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		equiv, _ := s.Attr("http-equiv")
+		if !strings.EqualFold(strings.TrimSpace(equiv), "refresh") {
+			return true
+		}
+		content, exists := s.Attr("content")
+		if !exists {
+			return true
+		}
+		_, u, ok := parseMetaRefreshContent(content)
+		if !ok {
+			return true
+		}
+		target, found = u, true
+		return false
+	})
+	return target, found
+}
+
+// parseMetaRefreshContent splits a meta-refresh content value like
+// "5; url=https://example.com/next" into its delay and URL parts.
+func parseMetaRefreshContent(content string) (delay string, u string, ok bool) {
+	parts := strings.SplitN(content, ";", 2)
+	delay = strings.TrimSpace(parts[0])
+	if len(parts) < 2 {
+		return delay, "", false
+	}
+	rest := strings.TrimSpace(parts[1])
+	i := strings.Index(strings.ToLower(rest), "url=")
+	if i < 0 {
+		return delay, "", false
+	}
+	u = strings.Trim(strings.TrimSpace(rest[i+len("url="):]), `"'`)
+	if u == "" {
+		return delay, "", false
+	}
+	return delay, u, true
+}
+
+// resolveLink trims href and resolves it against up per RFC 3986 (so a
+// path-relative href like "foo/bar" on https://example.com/a/b/page.html
+// resolves to https://example.com/a/b/foo/bar, not
+// https://example.com/foo/bar), applying
+// onlySameHost filtering against whatever host href itself named.
+func resolveLink(up *url.URL, href string, onlySameHost bool) (string, bool) {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return "", false
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	if onlySameHost && ref.Host != "" && !sameMainDomain(up.Host, ref.Host) {
+		return "", false
+	}
+	return up.ResolveReference(ref).String(), true
+}
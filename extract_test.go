@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestResolveLinkPathRelative(t *testing.T) {
+	up, err := url.Parse("https://example.com/a/b/page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := resolveLink(up, "foo/bar", false)
+	if !ok {
+		t.Fatal("resolveLink returned ok == false")
+	}
+	if want := "https://example.com/a/b/foo/bar"; got != want {
+		t.Fatalf("resolveLink(%q) = %q, want %q", "foo/bar", got, want)
+	}
+}
+
+func TestResolveLinkRootRelativeAndAbsolute(t *testing.T) {
+	up, err := url.Parse("https://example.com/a/b/page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := resolveLink(up, "/other", false); got != "https://example.com/other" {
+		t.Fatalf("root-relative resolveLink = %q", got)
+	}
+	if got, _ := resolveLink(up, "https://other.com/x", false); got != "https://other.com/x" {
+		t.Fatalf("absolute resolveLink = %q", got)
+	}
+}
+
+func TestResolveLinkOnlySameHost(t *testing.T) {
+	up, err := url.Parse("https://example.com/page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resolveLink(up, "https://other.com/x", true); ok {
+		t.Fatal("expected a different-domain link to be filtered out under onlySameHost")
+	}
+	if got, ok := resolveLink(up, "https://sub.example.com/x", true); !ok || got != "https://sub.example.com/x" {
+		t.Fatalf("expected a same-main-domain subdomain link to be kept, got %q, %v", got, ok)
+	}
+}
+
+func TestExtractLinksResolvesAgainstNonRootPage(t *testing.T) {
+	html := `<html><body><a href="foo/bar">rel</a><a href="/root">root</a></body></html>`
+	d, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	up, err := url.Parse("https://example.com/a/b/page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := extractLinks(d, up, false, nil)
+	want := map[string]bool{
+		"https://example.com/a/b/foo/bar": true,
+		"https://example.com/root":        true,
+	}
+	if len(links) != len(want) {
+		t.Fatalf("links = %v, want %v", links, want)
+	}
+	for _, l := range links {
+		if !want[l] {
+			t.Errorf("unexpected link %q", l)
+		}
+	}
+}
+
+func TestParseMetaRefreshContent(t *testing.T) {
+	delay, u, ok := parseMetaRefreshContent(`5; url=https://example.com/next`)
+	if !ok || delay != "5" || u != "https://example.com/next" {
+		t.Fatalf("parseMetaRefreshContent = %q, %q, %v", delay, u, ok)
+	}
+	if _, _, ok := parseMetaRefreshContent("5"); ok {
+		t.Fatal("expected ok == false when no url= part is present")
+	}
+}
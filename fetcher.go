@@ -0,0 +1,167 @@
+package crawler
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// Fetcher abstracts the retrieval of a URL so callers can swap in mocks,
+// caching layers, or alternative transports without touching the crawl
+// logic.
+type Fetcher interface {
+	Fetch(ctx context.Context, u string) (*http.Response, []byte, error)
+}
+
+// RetryPolicy controls how a Fetcher retries transient failures.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first one.
+	// Negative means a single try with no retries. Zero means the same
+	// on an HTTPFetcher built directly, but NewHTTPFetcher (and so
+	// Options) replaces a policy with zero MaxAttempts by
+	// DefaultRetryPolicy.
+	MaxAttempts int
+	// BaseDelay is the backoff used after the first failed attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between attempts.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is a conservative policy suitable for polite crawling.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+const defaultUserAgent = "crawler (+https://github.com/ypapax/crawler)"
+
+// HTTPFetcher is the default Fetcher implementation. It wraps an
+// *http.Client with a rate limiter and a retry policy that backs off on
+// 429 and 5xx responses, honoring Retry-After when present.
+type HTTPFetcher struct {
+	Client      *http.Client
+	Limiter     *rate.Limiter
+	RetryPolicy RetryPolicy
+	UserAgent   string
+}
+
+// NewHTTPFetcher builds an HTTPFetcher. A nil client becomes one with a
+// 30s timeout, a RetryPolicy with zero MaxAttempts becomes
+// DefaultRetryPolicy, and an empty userAgent the default one; a nil
+// limiter is kept, leaving requests unthrottled.
+func NewHTTPFetcher(client *http.Client, limiter *rate.Limiter, retryPolicy RetryPolicy, userAgent string) *HTTPFetcher {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if retryPolicy.MaxAttempts == 0 {
+		retryPolicy = DefaultRetryPolicy
+	}
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	return &HTTPFetcher{
+		Client:      client,
+		Limiter:     limiter,
+		RetryPolicy: retryPolicy,
+		UserAgent:   userAgent,
+	}
+}
+
+// Fetch performs a GET request for u, retrying on 429/5xx according to
+// f.RetryPolicy and waiting on f.Limiter (if set) before each attempt.
+func (f *HTTPFetcher) Fetch(ctx context.Context, u string) (resp *http.Response, body []byte, finalErr error) {
+	l := logrus.WithField("u", u)
+	attempts := f.RetryPolicy.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if f.Limiter != nil {
+			if err := f.Limiter.Wait(ctx); err != nil {
+				return nil, nil, errors.WithStack(err)
+			}
+		}
+		resp, body, lastErr = f.do(ctx, u)
+		if lastErr == nil && !shouldRetry(resp.StatusCode) {
+			return resp, body, nil
+		}
+		if lastErr == nil {
+			lastErr = errors.Errorf("retryable status code: %+v", resp.StatusCode)
+		}
+		if attempt == attempts {
+			break
+		}
+		delay := f.retryDelay(attempt, resp)
+		l.WithField("attempt", attempt).WithField("delay", delay).Infof("retrying after error: %+v", lastErr)
+		select {
+		case <-ctx.Done():
+			return nil, nil, errors.WithStack(ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+	return nil, nil, errors.WithStack(lastErr)
+}
+
+func (f *HTTPFetcher) do(ctx context.Context, u string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, errors.WithStack(err)
+	}
+	return resp, b, nil
+}
+
+func shouldRetry(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || (statusCode >= 500 && statusCode <= 599)
+}
+
+// retryDelay computes the backoff before the next attempt, honoring the
+// response's Retry-After header when present and otherwise using
+// exponential backoff with jitter, capped at MaxDelay.
+func (f *HTTPFetcher) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp); ok {
+			return d
+		}
+	}
+	delay := f.RetryPolicy.BaseDelay << (attempt - 1)
+	if f.RetryPolicy.MaxDelay > 0 && delay > f.RetryPolicy.MaxDelay {
+		delay = f.RetryPolicy.MaxDelay
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay/2 + jitter
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if when, err := http.ParseTime(v); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d, true
+		}
+	}
+	return 0, false
+}
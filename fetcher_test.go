@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubResponse is one canned response a stubTransport hands back, in
+// order, to successive requests.
+type stubResponse struct {
+	status int
+	body   string
+}
+
+// stubTransport is an http.RoundTripper serving a fixed sequence of
+// responses, for exercising HTTPFetcher's retry loop without a network.
+type stubTransport struct {
+	t         *testing.T
+	responses []stubResponse
+	next      int
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if s.next >= len(s.responses) {
+		s.t.Fatalf("stubTransport: unexpected request %d, only %d responses configured", s.next+1, len(s.responses))
+	}
+	r := s.responses[s.next]
+	s.next++
+	return &http.Response{
+		StatusCode: r.status,
+		Body:       io.NopCloser(strings.NewReader(r.body)),
+		Header:     http.Header{},
+		Request:    req,
+	}, nil
+}
+
+func newStubClient(t *testing.T, responses []stubResponse) *http.Client {
+	return &http.Client{Transport: &stubTransport{t: t, responses: responses}}
+}
+
+func TestShouldRetry(t *testing.T) {
+	cases := map[int]bool{
+		200: false,
+		404: false,
+		429: true,
+		500: true,
+		503: true,
+		599: true,
+		600: false,
+	}
+	for code, want := range cases {
+		if got := shouldRetry(code); got != want {
+			t.Errorf("shouldRetry(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	d, ok := retryAfter(resp)
+	if !ok || d != 2*time.Second {
+		t.Fatalf("retryAfter = %v, %v, want 2s, true", d, ok)
+	}
+}
+
+func TestRetryAfterHTTPDate(t *testing.T) {
+	when := time.Now().Add(5 * time.Second).UTC()
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{when.Format(http.TimeFormat)}}}
+	d, ok := retryAfter(resp)
+	if !ok || d <= 0 || d > 6*time.Second {
+		t.Fatalf("retryAfter = %v, %v, want a positive duration close to 5s", d, ok)
+	}
+}
+
+func TestRetryAfterAbsent(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	if _, ok := retryAfter(resp); ok {
+		t.Fatal("expected ok == false with no Retry-After header")
+	}
+}
+
+func TestRetryDelayHonorsRetryAfter(t *testing.T) {
+	f := &HTTPFetcher{RetryPolicy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}}
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	if got := f.retryDelay(1, resp); got != 3*time.Second {
+		t.Fatalf("retryDelay = %v, want 3s from Retry-After", got)
+	}
+}
+
+func TestRetryDelayCapsAtMaxDelay(t *testing.T) {
+	f := &HTTPFetcher{RetryPolicy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 2 * time.Second}}
+	// At attempt 10 exponential backoff would be far beyond MaxDelay;
+	// the jittered result must never exceed it.
+	for attempt := 1; attempt <= 10; attempt++ {
+		if got := f.retryDelay(attempt, nil); got > 2*time.Second {
+			t.Fatalf("retryDelay(%d) = %v, want <= MaxDelay 2s", attempt, got)
+		}
+	}
+}
+
+func TestHTTPFetcherRetriesOn5xxThenSucceeds(t *testing.T) {
+	client := newStubClient(t, []stubResponse{
+		{status: 503},
+		{status: 200, body: "ok"},
+	})
+	f := NewHTTPFetcher(client, nil, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}, "")
+	resp, body, err := f.Fetch(context.Background(), "https://example.com/")
+	if err != nil {
+		t.Fatalf("Fetch: %+v", err)
+	}
+	if resp.StatusCode != 200 || string(body) != "ok" {
+		t.Fatalf("resp = %+v, body = %q", resp, body)
+	}
+}
+
+func TestHTTPFetcherGivesUpAfterMaxAttempts(t *testing.T) {
+	client := newStubClient(t, []stubResponse{
+		{status: 500}, {status: 500}, {status: 500},
+	})
+	f := NewHTTPFetcher(client, nil, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}, "")
+	if _, _, err := f.Fetch(context.Background(), "https://example.com/"); err == nil {
+		t.Fatal("expected an error once MaxAttempts is exhausted")
+	}
+}
@@ -2,58 +2,155 @@ package crawler
 
 import (
 	"bytes"
-	"github.com/PuerkitoBio/goquery"
-	"github.com/pkg/errors"
-	"github.com/sirupsen/logrus"
-	"io/ioutil"
+	"context"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
 	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
-func Run(u string, timeout time.Duration, f CheckPageContentFunc, statusCodeMin, statusCodeMax int, onlySameHost bool, linksLimit int) error {
-	if err := parseRecursive(u, timeout, statusCodeMin, statusCodeMax, f, onlySameHost, linksLimit); err != nil {
-		return errors.WithStack(err)
+// Options configures a crawl. It replaces the long positional parameter
+// list Run used to take.
+type Options struct {
+	// URL is the seed page to start crawling from.
+	URL string
+	// Timeout bounds each individual request when Fetcher is nil, in
+	// which case a default Fetcher is built around it.
+	Timeout time.Duration
+	// StatusCodeMin and StatusCodeMax define the inclusive response
+	// status range considered acceptable; anything outside it is an
+	// error.
+	StatusCodeMin, StatusCodeMax int
+	// CheckPageContentFunc, if set, is invoked with the body of every
+	// fetched HTML page; returning an error records it as that page's
+	// PageResult.Err and stops its links from being followed, while the
+	// rest of the crawl goes on. A nil CheckPageContentFunc skips this
+	// check (use OnResponse instead if you need to inspect every
+	// response, HTML or not).
+	CheckPageContentFunc CheckPageContentFunc
+	// OnlySameHost restricts following links to the seed's main domain.
+	OnlySameHost bool
+	// LinksLimit stops the crawl once this many URLs have been
+	// requested. Zero means unlimited.
+	LinksLimit int
+
+	// Concurrency is the number of worker goroutines fetching pages in
+	// parallel. Values <= 1 make the crawl sequential.
+	Concurrency int
+	// MaxDepth caps how many hops away from URL are followed. Zero means
+	// unlimited.
+	MaxDepth int
+	// HostDelay is the minimum time between two requests to the same
+	// host, enforced across all workers.
+	HostDelay time.Duration
+
+	// Fetcher performs the actual HTTP fetches. If nil, a default
+	// HTTPFetcher is built from Timeout, UserAgent, RateLimiter and
+	// RetryPolicy.
+	Fetcher Fetcher
+	// UserAgent is sent on every request made by the default Fetcher.
+	UserAgent string
+	// RateLimiter, if set, is shared across all requests made by the
+	// default Fetcher to cap the crawl-wide request rate.
+	RateLimiter *rate.Limiter
+	// RetryPolicy configures retries on transient errors for the
+	// default Fetcher. The zero value falls back to DefaultRetryPolicy.
+	RetryPolicy RetryPolicy
+
+	// Robots controls robots.txt handling. If nil, an enabled
+	// RobotsPolicy is built automatically using UserAgent; to crawl
+	// without consulting robots.txt at all, pass a
+	// &RobotsPolicy{Disabled: true}.
+	Robots *RobotsPolicy
+	// DiscoverSitemaps, when set, seeds the frontier with the URLs
+	// found in the seed host's sitemap(s) in addition to URL itself.
+	DiscoverSitemaps bool
+
+	// LinkSelectors are appended to DefaultLinkSelectors to let callers
+	// discover links beyond the built-in a/link/area/iframe/img set,
+	// e.g. a custom data attribute a scraper relies on.
+	LinkSelectors []Selector
+	// OnResponse, if set, is invoked for every fetched response whose
+	// status code is within StatusCodeMin..StatusCodeMax, regardless of
+	// Content-Type, before any HTML-specific processing.
+	// Unlike CheckPageContentFunc it is not limited to HTML pages.
+	OnResponse func(u, contentType string, body []byte) error
+
+	// Store holds the frontier and visited set. If nil, a fresh
+	// NewMemoryStore is used. Passing a Store backed by BoltStore or
+	// RedisStore, and reusing it across Run/Crawl calls, lets a crawl
+	// resume where a previous one left off.
+	Store Store
+}
+
+func (o Options) fetcher() Fetcher {
+	if o.Fetcher != nil {
+		return o.Fetcher
 	}
-	return nil
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+	return NewHTTPFetcher(client, o.RateLimiter, o.RetryPolicy, o.UserAgent)
 }
 
-var (
-	requested = make(map[string]struct{})
-	requestedMtx sync.RWMutex
-)
+func (o Options) robotsPolicy(fetcher Fetcher) *RobotsPolicy {
+	if o.Robots != nil {
+		return o.Robots
+	}
+	return NewRobotsPolicy(fetcher, o.UserAgent)
+}
 
-type CheckPageContentFunc func(string)error
+func (o Options) store() Store {
+	if o.Store != nil {
+		return o.Store
+	}
+	return NewMemoryStore()
+}
 
-func parseRecursive(u string, timeout time.Duration, statusCodeMin, statusCodeMax int, f CheckPageContentFunc, onlySameHost bool, linksLimit int) error {
-	lf := logrus.WithField("parentUrl", u).WithField("linksLimit", linksLimit)
-	ll, err := parse(u, timeout, statusCodeMin, statusCodeMax, f, onlySameHost)
+// Run crawls starting from opts.URL until the frontier drains, ctx is
+// done, or the link limit is reached; it returns only ctx's error, as
+// per-page errors are reported in the PageResults that Run discards.
+// It is a convenience wrapper around
+// NewCrawler(opts).Crawl(ctx) for callers that don't need per-page
+// PageResults; it drains and discards Results() itself so Crawl never
+// blocks waiting for a consumer that doesn't exist.
+func Run(ctx context.Context, opts Options) error {
+	c := NewCrawler(opts)
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for range c.Results() {
+		}
+	}()
+	err := c.Crawl(ctx)
+	<-drained
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	for _, l := range ll {
-		if enough := func() bool {
-			if linksLimit == 0 {
-				return false
-			}
-			requestedMtx.RLock()
-			defer requestedMtx.RUnlock()
-			lf = lf.WithField("len(requested)", len(requested))
-			return len(requested) > linksLimit
-		}(); enough {
-			lf.Infof("this is enough links")
-			return nil
-		}
-		if errP := parseRecursive(l, timeout, statusCodeMin, statusCodeMax, f, onlySameHost, linksLimit); errP != nil {
-			return errors.WithStack(errP)
-		}
-	}
 	return nil
 }
 
-func parse(u string, timeout time.Duration, statusCodeMin, statusCodeMax int, f CheckPageContentFunc, onlySameHost bool) (links []string, finalErr error) {
+type CheckPageContentFunc func(string) error
+
+// fetchOutcome is what parse learns from fetching a single URL, before
+// the Crawler turns it into a PageResult.
+type fetchOutcome struct {
+	FinalURL   string
+	StatusCode int
+	Links      []string
+}
+
+// parse fetches u and extracts its outgoing links. It holds no crawl
+// state of its own; dedup, depth tracking and scheduling are the
+// Crawler's job.
+func parse(ctx context.Context, u string, fetcher Fetcher, opts Options) (out fetchOutcome, finalErr error) {
 	l := logrus.WithField("u", u)
 	t1 := time.Now()
 	defer func() {
@@ -62,94 +159,65 @@ func parse(u string, timeout time.Duration, statusCodeMin, statusCodeMax int, f
 		}
 		l.Infof("requested for %+v", time.Since(t1))
 	}()
-	isRequested := func() bool {
-		requestedMtx.RLock()
-		defer requestedMtx.RUnlock()
-		l = l.WithField("len(requested)", len(requested))
-		_, exists := requested[u]
-		return exists
-	}()
-	if isRequested {
-		l.Infof("it's already requested, skip it")
-		return nil, nil
-	}
 	l.Infof("requesting...")
-	cl := http.Client{Timeout: timeout}
-	resp, err := cl.Get(u)
+	resp, b, err := fetcher.Fetch(ctx, u)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return fetchOutcome{}, errors.WithStack(err)
+	}
+	out.StatusCode = resp.StatusCode
+	if resp.Request != nil && resp.Request.URL != nil {
+		out.FinalURL = resp.Request.URL.String()
+	} else {
+		out.FinalURL = u
 	}
-	if resp.StatusCode < statusCodeMin || resp.StatusCode > statusCodeMax {
-		return nil, errors.Errorf("bad status code: %+v, supported status code range: %+v - %+v", resp.StatusCode, statusCodeMin, statusCodeMax)
+	if resp.StatusCode < opts.StatusCodeMin || resp.StatusCode > opts.StatusCodeMax {
+		return out, errors.Errorf("bad status code: %+v, supported status code range: %+v - %+v", resp.StatusCode, opts.StatusCodeMin, opts.StatusCodeMax)
 	}
-	b, err := ioutil.ReadAll(resp.Body)
+	ctype := resp.Header.Get("Content-Type")
+	if opts.OnResponse != nil {
+		if errR := opts.OnResponse(u, ctype, b); errR != nil {
+			return out, errors.WithStack(errR)
+		}
+	}
+	up, err := url.Parse(out.FinalURL)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return out, errors.WithStack(err)
 	}
-	if errF := f(string(b)); errF != nil {
-		return nil, errors.WithStack(errF)
+	if !isHTML(ctype) {
+		return out, nil
 	}
-	r := bytes.NewReader(b)
-	d, err := goquery.NewDocumentFromReader(r)
+	if opts.CheckPageContentFunc != nil {
+		if errF := opts.CheckPageContentFunc(string(b)); errF != nil {
+			return out, errors.WithStack(errF)
+		}
+	}
+	d, err := goquery.NewDocumentFromReader(bytes.NewReader(b))
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return out, errors.WithStack(err)
 	}
-	func(){
-		requestedMtx.Lock()
-		defer requestedMtx.Unlock()
-		requested[u] = struct{}{}
-	}()
+	out.Links = append(out.Links, extractLinks(d, up, opts.OnlySameHost, opts.LinkSelectors)...)
+	return out, nil
+}
 
-	up, err := url.Parse(u)
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
-	aa := d.Find("a")
-	done := make(chan struct{})
-	errs := make(chan error, aa.Length())
-	go func(){
-		aa.Each(func(i int, s *goquery.Selection) {
-			href, exists := s.Attr("href")
-			if !exists {
-				return
-			}
-			href = strings.TrimSpace(href)
-			if len(href) == 0 {
-				return
-			}
-			up2, err2 := url.Parse(href)
-			if err2 != nil {
-				errs <- err2
-				return
-			}
-			if onlySameHost {
-				if len(up2.Host) != 0 && !sameMainDomain(up.Host, up2.Host) {
-					return
-				}
-			}
-			if up2.Host == "" {
-				up2.Host = up.Host
-			}
-			if len(up2.Scheme) == 0 {
-				up2.Scheme = up.Scheme
-			}
-			links = append(links, up2.String())
-		})
-		done <- struct{}{}
-	}()
-	select {
-	case <-done:
-	case errCh := <-errs:
-		return nil, errors.WithStack(errCh)
+// isHTML reports whether contentType names an HTML document. A missing
+// Content-Type is treated as HTML, matching net/http's own sniffing
+// fallback and keeping plain test fixtures working.
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
 	}
-	return links, nil
+	mediaType := contentType
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	return strings.EqualFold(strings.TrimSpace(mediaType), "text/html")
 }
 
 // Converts sub.domain.com to domain.com
 // and sub2.sub.domain.com to domain.com
 func mainDomain(host string) string {
 	const (
-		subDomainSep = "."
+		subDomainSep    = "."
 		mainDomainParts = 2
 	)
 	pp := strings.Split(host, subDomainSep)
@@ -161,4 +229,4 @@ func mainDomain(host string) string {
 
 func sameMainDomain(host1, host2 string) bool {
 	return mainDomain(host1) == mainDomain(host2)
-}
\ No newline at end of file
+}
@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestIsHTML(t *testing.T) {
+	cases := map[string]bool{
+		"":                         true,
+		"text/html":                true,
+		"text/html; charset=utf-8": true,
+		"Text/HTML":                true,
+		"application/json":         false,
+		"image/png":                false,
+		"application/xml; q=0.9":   false,
+	}
+	for ct, want := range cases {
+		if got := isHTML(ct); got != want {
+			t.Errorf("isHTML(%q) = %v, want %v", ct, got, want)
+		}
+	}
+}
+
+func TestMainDomain(t *testing.T) {
+	cases := map[string]string{
+		"example.com":     "example.com",
+		"www.example.com": "example.com",
+		"a.b.example.com": "example.com",
+		"localhost":       "localhost",
+	}
+	for host, want := range cases {
+		if got := mainDomain(host); got != want {
+			t.Errorf("mainDomain(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+// singlePageFetcher always returns the same canned response, regardless
+// of the requested URL.
+type singlePageFetcher struct {
+	status      int
+	contentType string
+	body        string
+}
+
+func (f singlePageFetcher) Fetch(ctx context.Context, u string) (*http.Response, []byte, error) {
+	return &http.Response{
+		StatusCode: f.status,
+		Header:     http.Header{"Content-Type": []string{f.contentType}},
+		Request:    &http.Request{URL: mustParseURL(u)},
+	}, []byte(f.body), nil
+}
+
+func TestParseSkipsLinkExtractionForNonHTML(t *testing.T) {
+	fetcher := singlePageFetcher{status: 200, contentType: "application/json", body: `{"a":1}`}
+	out, err := parse(context.Background(), "https://example.com/api", fetcher, Options{StatusCodeMin: 200, StatusCodeMax: 299})
+	if err != nil {
+		t.Fatalf("parse: %+v", err)
+	}
+	if len(out.Links) != 0 {
+		t.Fatalf("expected no links extracted from a non-HTML response, got %v", out.Links)
+	}
+}
+
+func TestParseInvokesOnResponseForEveryContentType(t *testing.T) {
+	fetcher := singlePageFetcher{status: 200, contentType: "application/json", body: `{}`}
+	var gotContentType string
+	opts := Options{
+		StatusCodeMin: 200,
+		StatusCodeMax: 299,
+		OnResponse: func(u, contentType string, body []byte) error {
+			gotContentType = contentType
+			return nil
+		},
+	}
+	if _, err := parse(context.Background(), "https://example.com/api", fetcher, opts); err != nil {
+		t.Fatalf("parse: %+v", err)
+	}
+	if gotContentType != "application/json" {
+		t.Fatalf("OnResponse saw content-type %q, want application/json", gotContentType)
+	}
+}
+
+func TestParseBadStatusCodeIsAnError(t *testing.T) {
+	fetcher := singlePageFetcher{status: 500, contentType: "text/html"}
+	_, err := parse(context.Background(), "https://example.com/", fetcher, Options{StatusCodeMin: 200, StatusCodeMax: 299})
+	if err == nil {
+		t.Fatal("expected an error for a status code outside the configured range")
+	}
+}
+
+func TestParseExtractsLinksFromHTML(t *testing.T) {
+	fetcher := singlePageFetcher{status: 200, contentType: "text/html", body: `<a href="/next">next</a>`}
+	out, err := parse(context.Background(), "https://example.com/", fetcher, Options{StatusCodeMin: 200, StatusCodeMax: 299})
+	if err != nil {
+		t.Fatalf("parse: %+v", err)
+	}
+	if len(out.Links) != 1 || out.Links[0] != "https://example.com/next" {
+		t.Fatalf("out.Links = %v", out.Links)
+	}
+}
@@ -0,0 +1,180 @@
+package crawler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PageResult reports the outcome of fetching a single URL during a
+// crawl, streamed from Crawler.Results as the crawl runs.
+type PageResult struct {
+	URL        string
+	FinalURL   string
+	StatusCode int
+	Depth      int
+	ParentURL  string
+	Duration   time.Duration
+	Links      []string
+	Err        error
+}
+
+type pageResultJSON struct {
+	URL        string   `json:"url"`
+	FinalURL   string   `json:"final_url,omitempty"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Depth      int      `json:"depth"`
+	ParentURL  string   `json:"parent_url,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+	Links      []string `json:"links,omitempty"`
+	Err        string   `json:"error,omitempty"`
+}
+
+// MarshalJSON renders Err as a string, since error values don't encode
+// on their own.
+func (r PageResult) MarshalJSON() ([]byte, error) {
+	j := pageResultJSON{
+		URL:        r.URL,
+		FinalURL:   r.FinalURL,
+		StatusCode: r.StatusCode,
+		Depth:      r.Depth,
+		ParentURL:  r.ParentURL,
+		DurationMS: r.Duration.Milliseconds(),
+		Links:      r.Links,
+	}
+	if r.Err != nil {
+		j.Err = r.Err.Error()
+	}
+	return json.Marshal(j)
+}
+
+// WriteJSONL drains results, writing one JSON object per line to w.
+func WriteJSONL(w io.Writer, results <-chan PageResult) error {
+	enc := json.NewEncoder(w)
+	for r := range results {
+		if err := enc.Encode(r); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+var csvHeader = []string{"url", "final_url", "status_code", "depth", "parent_url", "duration_ms", "links", "error"}
+
+// WriteCSV drains results, writing a header row followed by one row per
+// result to w. Links are encoded as a JSON array in their column, since
+// links come straight from extractLinks rather than through
+// normalizeURL's percent-encoding and so may themselves contain any
+// separator a plain join would pick.
+func WriteCSV(w io.Writer, results <-chan PageResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return errors.WithStack(err)
+	}
+	for r := range results {
+		errMsg := ""
+		if r.Err != nil {
+			errMsg = r.Err.Error()
+		}
+		links, err := joinLinks(r.Links)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		record := []string{
+			r.URL,
+			r.FinalURL,
+			strconv.Itoa(r.StatusCode),
+			strconv.Itoa(r.Depth),
+			r.ParentURL,
+			strconv.FormatInt(r.Duration.Milliseconds(), 10),
+			links,
+			errMsg,
+		}
+		if err := cw.Write(record); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	cw.Flush()
+	return errors.WithStack(cw.Error())
+}
+
+// joinLinks renders links as a JSON array so that a link containing a
+// literal separator character can't be confused with two links.
+func joinLinks(links []string) (string, error) {
+	if len(links) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(links)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline,omitempty"`
+}
+
+// WriteOPML drains results and writes an OPML document that groups
+// every discovered link by host, one outline per host.
+func WriteOPML(w io.Writer, results <-chan PageResult) error {
+	byHost := make(map[string]map[string]struct{})
+	var hosts []string
+	for r := range results {
+		for _, link := range r.Links {
+			up, err := url.Parse(link)
+			if err != nil || up.Host == "" {
+				continue
+			}
+			if byHost[up.Host] == nil {
+				byHost[up.Host] = make(map[string]struct{})
+				hosts = append(hosts, up.Host)
+			}
+			byHost[up.Host][link] = struct{}{}
+		}
+	}
+	sort.Strings(hosts)
+
+	doc := opmlDocument{Version: "2.0"}
+	for _, host := range hosts {
+		links := make([]string, 0, len(byHost[host]))
+		for l := range byHost[host] {
+			links = append(links, l)
+		}
+		sort.Strings(links)
+		outline := opmlOutline{Text: host}
+		for _, l := range links {
+			outline.Outlines = append(outline.Outlines, opmlOutline{Text: l, XMLURL: l})
+		}
+		doc.Body.Outlines = append(doc.Body.Outlines, outline)
+	}
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return errors.WithStack(err)
+	}
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = w.Write(b)
+	return errors.WithStack(err)
+}
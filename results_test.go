@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestJoinLinksEscapesSeparatorCharacters(t *testing.T) {
+	links := []string{"https://example.com/a|b", "https://example.com/c"}
+	joined, err := joinLinks(links)
+	if err != nil {
+		t.Fatalf("joinLinks: %+v", err)
+	}
+	var got []string
+	if err := json.Unmarshal([]byte(joined), &got); err != nil {
+		t.Fatalf("joined links %q did not round-trip as JSON: %+v", joined, err)
+	}
+	if len(got) != 2 || got[0] != links[0] || got[1] != links[1] {
+		t.Fatalf("round-tripped links = %v, want %v", got, links)
+	}
+}
+
+func TestJoinLinksEmpty(t *testing.T) {
+	got, err := joinLinks(nil)
+	if err != nil || got != "" {
+		t.Fatalf("joinLinks(nil) = %q, %v, want \"\", nil", got, err)
+	}
+}
+
+func results(rs ...PageResult) <-chan PageResult {
+	ch := make(chan PageResult, len(rs))
+	for _, r := range rs {
+		ch <- r
+	}
+	close(ch)
+	return ch
+}
+
+func TestWriteJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteJSONL(&buf, results(
+		PageResult{URL: "https://example.com/a", StatusCode: 200, Links: []string{"https://example.com/b"}},
+		PageResult{URL: "https://example.com/b", StatusCode: 404},
+	))
+	if err != nil {
+		t.Fatalf("WriteJSONL: %+v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+	var first pageResultJSON
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("unmarshal first line: %+v", err)
+	}
+	if first.URL != "https://example.com/a" || first.StatusCode != 200 {
+		t.Fatalf("first = %+v", first)
+	}
+}
+
+func TestWriteCSVEscapesLinksWithSeparator(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, results(
+		PageResult{URL: "https://example.com/a", Links: []string{"https://example.com/x|y", "https://example.com/z"}},
+	))
+	if err != nil {
+		t.Fatalf("WriteCSV: %+v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing written CSV: %+v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2 (header + 1)", len(rows))
+	}
+	linksCol := rows[1][6]
+	var links []string
+	if err := json.Unmarshal([]byte(linksCol), &links); err != nil {
+		t.Fatalf("links column %q is not valid JSON: %+v", linksCol, err)
+	}
+	if len(links) != 2 || links[0] != "https://example.com/x|y" || links[1] != "https://example.com/z" {
+		t.Fatalf("links = %v, want the 2 original links with the | preserved", links)
+	}
+}
+
+func TestWriteOPMLGroupsByHost(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteOPML(&buf, results(
+		PageResult{URL: "https://example.com/a", Links: []string{"https://b.com/1", "https://a.com/2"}},
+		PageResult{URL: "https://example.com/b", Links: []string{"https://a.com/2"}},
+	))
+	if err != nil {
+		t.Fatalf("WriteOPML: %+v", err)
+	}
+	var doc opmlDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("unmarshal OPML: %+v", err)
+	}
+	if len(doc.Body.Outlines) != 2 {
+		t.Fatalf("got %d host outlines, want 2", len(doc.Body.Outlines))
+	}
+	if doc.Body.Outlines[0].Text != "a.com" || doc.Body.Outlines[1].Text != "b.com" {
+		t.Fatalf("outlines = %+v, want hosts sorted a.com, b.com", doc.Body.Outlines)
+	}
+	if len(doc.Body.Outlines[0].Outlines) != 1 {
+		t.Fatalf("a.com outline should dedup the link seen on both pages, got %+v", doc.Body.Outlines[0].Outlines)
+	}
+}
@@ -0,0 +1,247 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// RobotsPolicy fetches and caches robots.txt per host and answers
+// whether a URL may be fetched, honoring Disallow/Allow for the most
+// specific matching User-agent group plus any declared Crawl-delay. A
+// nil *RobotsPolicy, or one with Disabled set, allows everything.
+type RobotsPolicy struct {
+	Fetcher   Fetcher
+	UserAgent string
+	Disabled  bool
+	// CrawlDelayOverride, when positive, replaces whatever Crawl-delay
+	// (if any) robots.txt declares for every host.
+	CrawlDelayOverride time.Duration
+
+	mu    sync.Mutex
+	rules map[string]*robotsRules
+}
+
+// NewRobotsPolicy builds an enabled RobotsPolicy that fetches robots.txt
+// with fetcher, identifying itself with userAgent.
+func NewRobotsPolicy(fetcher Fetcher, userAgent string) *RobotsPolicy {
+	return &RobotsPolicy{
+		Fetcher:   fetcher,
+		UserAgent: userAgent,
+		rules:     make(map[string]*robotsRules),
+	}
+}
+
+type robotsRules struct {
+	group      *robotsGroup
+	crawlDelay time.Duration
+	sitemaps   []string
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	path  string
+	allow bool
+}
+
+// Allowed reports whether u may be fetched under the cached robots.txt
+// rules for its host.
+func (p *RobotsPolicy) Allowed(ctx context.Context, u string) (bool, error) {
+	if p == nil || p.Disabled {
+		return true, nil
+	}
+	up, err := url.Parse(u)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	r := p.rulesFor(ctx, up)
+	return r.group.allowed(up.Path), nil
+}
+
+// CrawlDelay returns the Crawl-delay robots.txt declares for u's host,
+// or CrawlDelayOverride when set. Zero means none was declared.
+func (p *RobotsPolicy) CrawlDelay(ctx context.Context, u string) (time.Duration, error) {
+	if p == nil || p.Disabled {
+		return 0, nil
+	}
+	up, err := url.Parse(u)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return p.rulesFor(ctx, up).crawlDelay, nil
+}
+
+// Sitemaps returns the Sitemap: URLs robots.txt declares for u's host.
+func (p *RobotsPolicy) Sitemaps(ctx context.Context, u string) ([]string, error) {
+	if p == nil || p.Disabled {
+		return nil, nil
+	}
+	up, err := url.Parse(u)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return p.rulesFor(ctx, up).sitemaps, nil
+}
+
+// rulesFor returns the cached robotsRules for up's host, fetching and
+// parsing robots.txt on first use. A fetch failure or missing
+// robots.txt is treated as "allow everything", which is also returned
+// for hosts that couldn't be fetched so callers never have to special
+// case errors.
+func (p *RobotsPolicy) rulesFor(ctx context.Context, up *url.URL) *robotsRules {
+	host := strings.ToLower(up.Host)
+	p.mu.Lock()
+	if r, ok := p.rules[host]; ok {
+		p.mu.Unlock()
+		return r
+	}
+	p.mu.Unlock()
+
+	r := p.fetchRules(ctx, up)
+	if p.CrawlDelayOverride > 0 {
+		r.crawlDelay = p.CrawlDelayOverride
+	}
+
+	p.mu.Lock()
+	p.rules[host] = r
+	p.mu.Unlock()
+	return r
+}
+
+func (p *RobotsPolicy) fetchRules(ctx context.Context, up *url.URL) *robotsRules {
+	robotsURL := (&url.URL{Scheme: up.Scheme, Host: up.Host, Path: "/robots.txt"}).String()
+	resp, body, err := p.Fetcher.Fetch(ctx, robotsURL)
+	if err != nil {
+		logrus.WithField("host", up.Host).Infof("robots.txt unavailable, allowing all: %+v", err)
+		return &robotsRules{}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &robotsRules{}
+	}
+	groups, sitemaps := parseRobots(body)
+	ua := p.UserAgent
+	if ua == "" {
+		ua = defaultUserAgent
+	}
+	group := selectRobotsGroup(groups, ua)
+	r := &robotsRules{group: group, sitemaps: sitemaps}
+	if group != nil {
+		r.crawlDelay = group.crawlDelay
+	}
+	return r
+}
+
+// parseRobots parses a robots.txt body into its User-agent groups and
+// any Sitemap: directives. Wildcards in Disallow/Allow paths are not
+// supported, only plain prefixes, which covers the vast majority of
+// real robots.txt files.
+func parseRobots(body []byte) (groups []robotsGroup, sitemaps []string) {
+	var current *robotsGroup
+	flush := func() {
+		if current != nil {
+			groups = append(groups, *current)
+			current = nil
+		}
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:idx]))
+		val := strings.TrimSpace(line[idx+1:])
+		switch key {
+		case "user-agent":
+			if current != nil && len(current.rules) > 0 {
+				flush()
+			}
+			if current == nil {
+				current = &robotsGroup{}
+			}
+			current.agents = append(current.agents, strings.ToLower(val))
+		case "disallow":
+			if current == nil {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{path: val, allow: val == ""})
+		case "allow":
+			if current == nil {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{path: val, allow: true})
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			if secs, err := strconv.ParseFloat(val, 64); err == nil {
+				current.crawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		case "sitemap":
+			sitemaps = append(sitemaps, val)
+		}
+	}
+	flush()
+	return groups, sitemaps
+}
+
+// selectRobotsGroup picks the group whose agent token is the most
+// specific match for userAgent, falling back to the wildcard "*" group.
+func selectRobotsGroup(groups []robotsGroup, userAgent string) *robotsGroup {
+	ua := strings.ToLower(userAgent)
+	var wildcard, best *robotsGroup
+	bestLen := 0
+	for i := range groups {
+		for _, a := range groups[i].agents {
+			if a == "*" {
+				wildcard = &groups[i]
+				continue
+			}
+			if len(a) > bestLen && strings.Contains(ua, a) {
+				best, bestLen = &groups[i], len(a)
+			}
+		}
+	}
+	if best != nil {
+		return best
+	}
+	return wildcard
+}
+
+// allowed reports whether path is allowed under g, the longest matching
+// Disallow/Allow rule winning. A nil group, or no matching rule, allows.
+func (g *robotsGroup) allowed(path string) bool {
+	if g == nil {
+		return true
+	}
+	allow := true
+	bestLen := -1
+	for _, r := range g.rules {
+		if !strings.HasPrefix(path, r.path) {
+			continue
+		}
+		if len(r.path) > bestLen {
+			bestLen = len(r.path)
+			allow = r.allow
+		}
+	}
+	return allow
+}
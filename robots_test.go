@@ -0,0 +1,73 @@
+package crawler
+
+import "testing"
+
+func TestParseRobotsGroupsAndSitemaps(t *testing.T) {
+	body := []byte(`
+# comment lines are ignored
+User-agent: *
+Disallow: /private
+Crawl-delay: 2
+
+User-agent: Googlebot
+Disallow: /
+Allow: /public
+
+Sitemap: https://example.com/sitemap.xml
+Sitemap: https://example.com/news-sitemap.xml
+`)
+	groups, sitemaps := parseRobots(body)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if got, want := sitemaps, []string{"https://example.com/sitemap.xml", "https://example.com/news-sitemap.xml"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("sitemaps = %v, want %v", got, want)
+	}
+
+	wildcard := selectRobotsGroup(groups, "some-bot/1.0")
+	if wildcard == nil || wildcard.crawlDelay.Seconds() != 2 {
+		t.Fatalf("wildcard group = %+v, want crawl-delay 2s", wildcard)
+	}
+	if wildcard.allowed("/private/x") {
+		t.Error("expected /private/x disallowed for wildcard group")
+	}
+	if !wildcard.allowed("/other") {
+		t.Error("expected /other allowed for wildcard group")
+	}
+
+	googlebot := selectRobotsGroup(groups, "Mozilla/5.0 (compatible; Googlebot/2.1)")
+	if googlebot == nil {
+		t.Fatal("expected a Googlebot-specific group to be selected")
+	}
+	if googlebot.allowed("/anything") {
+		t.Error("expected /anything disallowed for Googlebot group")
+	}
+}
+
+func TestRobotsGroupAllowedLongestRuleWins(t *testing.T) {
+	g := &robotsGroup{rules: []robotsRule{
+		{path: "/a", allow: false},
+		{path: "/a/b", allow: true},
+		{path: "/a/b/c", allow: false},
+	}}
+	cases := map[string]bool{
+		"/a":       false,
+		"/a/b":     true,
+		"/a/b/x":   true,
+		"/a/b/c":   false,
+		"/a/b/c/d": false,
+		"/other":   true,
+	}
+	for path, want := range cases {
+		if got := g.allowed(path); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestRobotsGroupAllowedNilGroup(t *testing.T) {
+	var g *robotsGroup
+	if !g.allowed("/anything") {
+		t.Error("nil group should allow everything")
+	}
+}
@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// maxSitemapDepth bounds how deep DiscoverSitemapURLs follows nested
+// sitemap indexes, guarding against malformed or cyclic sitemaps.
+const maxSitemapDepth = 5
+
+type sitemapIndex struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	URLs    []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+}
+
+// DiscoverSitemapURLs seeds a frontier from the sitemaps declared in
+// seedURL's robots.txt (if robots is non-nil) plus the conventional
+// /sitemap.xml, following nested sitemap indexes. A missing or
+// unparseable sitemap is not an error, since sitemap discovery is best
+// effort.
+func DiscoverSitemapURLs(ctx context.Context, robots *RobotsPolicy, fetcher Fetcher, seedURL string) ([]string, error) {
+	up, err := url.Parse(seedURL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	candidates, err := robots.Sitemaps(ctx, seedURL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	candidates = append(candidates, (&url.URL{Scheme: up.Scheme, Host: up.Host, Path: "/sitemap.xml"}).String())
+
+	seen := make(map[string]struct{}, len(candidates))
+	var urls []string
+	for _, c := range candidates {
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+		found, err := fetchSitemap(ctx, fetcher, c, 0)
+		if err != nil {
+			continue
+		}
+		urls = append(urls, found...)
+	}
+	return urls, nil
+}
+
+func fetchSitemap(ctx context.Context, fetcher Fetcher, sitemapURL string, depth int) ([]string, error) {
+	if depth > maxSitemapDepth {
+		return nil, errors.Errorf("sitemap index nesting too deep at %+v", sitemapURL)
+	}
+	resp, body, err := fetcher.Fetch(ctx, sitemapURL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("bad status code for sitemap %+v: %+v", sitemapURL, resp.StatusCode)
+	}
+
+	var idx sitemapIndex
+	if err := xml.Unmarshal(body, &idx); err == nil && len(idx.Sitemaps) > 0 {
+		var urls []string
+		for _, s := range idx.Sitemaps {
+			nested, err := fetchSitemap(ctx, fetcher, s.Loc, depth+1)
+			if err != nil {
+				continue
+			}
+			urls = append(urls, nested...)
+		}
+		return urls, nil
+	}
+
+	var set sitemapURLSet
+	if err := xml.Unmarshal(body, &set); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	urls := make([]string, 0, len(set.URLs))
+	for _, u := range set.URLs {
+		urls = append(urls, u.Loc)
+	}
+	return urls, nil
+}
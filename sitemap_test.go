@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// fakeSitemapFetcher serves canned bodies for a fixed set of URLs, for
+// exercising fetchSitemap's nested-index handling without a network.
+type fakeSitemapFetcher map[string]string
+
+func (f fakeSitemapFetcher) Fetch(ctx context.Context, u string) (*http.Response, []byte, error) {
+	body, ok := f[u]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound}, nil, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK}, []byte(body), nil
+}
+
+func TestFetchSitemapFlatURLSet(t *testing.T) {
+	f := fakeSitemapFetcher{
+		"https://example.com/sitemap.xml": `<?xml version="1.0"?>
+<urlset><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`,
+	}
+	urls, err := fetchSitemap(context.Background(), f, "https://example.com/sitemap.xml", 0)
+	if err != nil {
+		t.Fatalf("fetchSitemap: %+v", err)
+	}
+	if len(urls) != 2 || urls[0] != "https://example.com/a" || urls[1] != "https://example.com/b" {
+		t.Fatalf("urls = %v", urls)
+	}
+}
+
+func TestFetchSitemapNestedIndex(t *testing.T) {
+	f := fakeSitemapFetcher{
+		"https://example.com/sitemap-index.xml": `<?xml version="1.0"?>
+<sitemapindex>
+  <sitemap><loc>https://example.com/sitemap-1.xml</loc></sitemap>
+  <sitemap><loc>https://example.com/sitemap-2.xml</loc></sitemap>
+</sitemapindex>`,
+		"https://example.com/sitemap-1.xml": `<?xml version="1.0"?>
+<urlset><url><loc>https://example.com/a</loc></url></urlset>`,
+		"https://example.com/sitemap-2.xml": `<?xml version="1.0"?>
+<urlset><url><loc>https://example.com/b</loc></url></urlset>`,
+	}
+	urls, err := fetchSitemap(context.Background(), f, "https://example.com/sitemap-index.xml", 0)
+	if err != nil {
+		t.Fatalf("fetchSitemap: %+v", err)
+	}
+	if len(urls) != 2 || urls[0] != "https://example.com/a" || urls[1] != "https://example.com/b" {
+		t.Fatalf("urls = %v", urls)
+	}
+}
+
+func TestFetchSitemapDepthCap(t *testing.T) {
+	f := fakeSitemapFetcher{}
+	// A self-referential index is the worst case; the depth cap must
+	// still terminate rather than recursing forever. Each nested failure
+	// is swallowed by the caller's loop, so the top level just comes
+	// back with no URLs instead of erroring.
+	f["https://example.com/loop.xml"] = `<?xml version="1.0"?>
+<sitemapindex><sitemap><loc>https://example.com/loop.xml</loc></sitemap></sitemapindex>`
+	urls, err := fetchSitemap(context.Background(), f, "https://example.com/loop.xml", 0)
+	if err != nil {
+		t.Fatalf("fetchSitemap: %+v", err)
+	}
+	if len(urls) != 0 {
+		t.Fatalf("urls = %v, want none once the depth cap is exceeded", urls)
+	}
+}
@@ -0,0 +1,173 @@
+package crawler
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// Store is the durable frontier and visited-set a Crawler drives a
+// crawl through. Implementations let a crawl survive process restarts:
+// on startup, a Crawler hydrates its frontier from whatever Enqueue
+// calls a prior run left behind and Seen already reflects every URL
+// that run touched, so the crawl resumes instead of starting over.
+type Store interface {
+	// Seen reports whether url has already been marked.
+	Seen(url string) bool
+	// Mark records url as seen. It is idempotent.
+	Mark(url string)
+	// TryMark atomically marks url as seen and reports whether this call
+	// was the one that did so (true the first time url is marked, false
+	// on every call after). Callers that need check-and-set semantics
+	// must use this instead of a separate Seen followed by Mark, which
+	// races under concurrent callers.
+	TryMark(url string) bool
+	// Enqueue adds url at depth to the frontier.
+	Enqueue(url string, depth int)
+	// Dequeue removes and returns the oldest frontier entry, or
+	// ok == false if the frontier is currently empty.
+	Dequeue() (url string, depth int, ok bool)
+}
+
+// MemoryStore is the default, in-process Store. Seen is backed by a
+// bloom filter so memory stays bounded on crawls touching millions of
+// URLs; an exact set in front of it keeps small-to-medium crawls free
+// of false positives, up to exactCap entries (DefaultExactCap for
+// NewMemoryStore, set by NewMemoryStoreSized). Past that cap the bloom
+// filter's small false-positive rate becomes the only protection
+// against revisits, trading a little precision for bounded memory.
+type MemoryStore struct {
+	mu       sync.Mutex
+	bloom    *bloomFilter
+	exact    map[string]struct{}
+	exactCap int
+	queue    []queueEntry
+}
+
+type queueEntry struct {
+	url   string
+	depth int
+}
+
+// DefaultExactCap is the number of URLs MemoryStore tracks exactly
+// before falling back to the bloom filter alone.
+const DefaultExactCap = 200_000
+
+// NewMemoryStore builds a MemoryStore sized for large crawls (a bloom
+// filter holding ~10M bits, good for roughly a million URLs at a ~1%
+// false-positive rate) with DefaultExactCap exact entries in front of
+// it.
+func NewMemoryStore() *MemoryStore {
+	return NewMemoryStoreSized(10_000_000, DefaultExactCap)
+}
+
+// NewMemoryStoreSized builds a MemoryStore with a bloom filter of
+// bloomBits bits and an exact set capped at exactCap entries.
+func NewMemoryStoreSized(bloomBits uint64, exactCap int) *MemoryStore {
+	return &MemoryStore{
+		bloom:    newBloomFilter(bloomBits, 4),
+		exact:    make(map[string]struct{}),
+		exactCap: exactCap,
+	}
+}
+
+func (s *MemoryStore) Seen(url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Below the cap the exact set is authoritative, so a miss there is
+	// a real miss. Above it we no longer know for sure, and trust the
+	// bloom filter's "maybe" to avoid unbounded memory growth.
+	return s.seenLocked(url)
+}
+
+func (s *MemoryStore) Mark(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markLocked(url)
+}
+
+func (s *MemoryStore) TryMark(url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seenLocked(url) {
+		return false
+	}
+	s.markLocked(url)
+	return true
+}
+
+func (s *MemoryStore) seenLocked(url string) bool {
+	if !s.bloom.mightContain(url) {
+		return false
+	}
+	if _, ok := s.exact[url]; ok {
+		return true
+	}
+	return len(s.exact) >= s.exactCap
+}
+
+func (s *MemoryStore) markLocked(url string) {
+	s.bloom.add(url)
+	if len(s.exact) < s.exactCap {
+		s.exact[url] = struct{}{}
+	}
+}
+
+func (s *MemoryStore) Enqueue(url string, depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, queueEntry{url: url, depth: depth})
+}
+
+func (s *MemoryStore) Dequeue() (string, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return "", 0, false
+	}
+	e := s.queue[0]
+	s.queue = s.queue[1:]
+	return e.url, e.depth, true
+}
+
+// bloomFilter is a minimal fixed-size bloom filter using double
+// hashing (two FNV variants combined) to derive its k hash functions,
+// avoiding a dependency on an external bloom filter package.
+type bloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    int
+}
+
+func newBloomFilter(m uint64, k int) *bloomFilter {
+	if m == 0 {
+		m = 1
+	}
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+func (b *bloomFilter) add(s string) {
+	h1, h2 := bloomHashes(s)
+	for i := 0; i < b.k; i++ {
+		idx := (h1 + uint64(i)*h2) % b.m
+		b.bits[idx/64] |= 1 << (idx % 64)
+	}
+}
+
+func (b *bloomFilter) mightContain(s string) bool {
+	h1, h2 := bloomHashes(s)
+	for i := 0; i < b.k; i++ {
+		idx := (h1 + uint64(i)*h2) % b.m
+		if b.bits[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func bloomHashes(s string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write([]byte(s))
+	h2 := fnv.New64()
+	h2.Write([]byte(s))
+	return h1.Sum64(), h2.Sum64()
+}
@@ -0,0 +1,136 @@
+package crawler
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltSeenBucket  = []byte("seen")
+	boltQueueBucket = []byte("queue")
+)
+
+// BoltStore is an on-disk Store backed by a BoltDB file, so a crawl can
+// be resumed after the process restarts by reopening the same path.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (creating if necessary) a BoltDB-backed Store at
+// path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(boltSeenBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(boltQueueBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.WithStack(err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close releases the underlying BoltDB file.
+func (s *BoltStore) Close() error {
+	return errors.WithStack(s.db.Close())
+}
+
+func (s *BoltStore) Seen(url string) bool {
+	var seen bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		seen = tx.Bucket(boltSeenBucket).Get([]byte(url)) != nil
+		return nil
+	})
+	if err != nil {
+		logrus.WithField("url", url).Warnf("bolt store: Seen failed: %+v", err)
+	}
+	return seen
+}
+
+func (s *BoltStore) Mark(url string) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltSeenBucket).Put([]byte(url), []byte{1})
+	})
+	if err != nil {
+		logrus.WithField("url", url).Warnf("bolt store: Mark failed: %+v", err)
+	}
+}
+
+// TryMark checks and sets url as seen inside a single read-write
+// transaction, so concurrent callers can't both observe url as unseen.
+func (s *BoltStore) TryMark(url string) bool {
+	var marked bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltSeenBucket)
+		if b.Get([]byte(url)) != nil {
+			return nil
+		}
+		marked = true
+		return b.Put([]byte(url), []byte{1})
+	})
+	if err != nil {
+		logrus.WithField("url", url).Warnf("bolt store: TryMark failed: %+v", err)
+		return false
+	}
+	return marked
+}
+
+type boltQueueEntry struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+func (s *BoltStore) Enqueue(url string, depth int) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltQueueBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		val, err := json.Marshal(boltQueueEntry{URL: url, Depth: depth})
+		if err != nil {
+			return err
+		}
+		return b.Put(key, val)
+	})
+	if err != nil {
+		logrus.WithField("url", url).Warnf("bolt store: Enqueue failed: %+v", err)
+	}
+}
+
+func (s *BoltStore) Dequeue() (url string, depth int, ok bool) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltQueueBucket)
+		k, v := b.Cursor().First()
+		if k == nil {
+			return nil
+		}
+		var e boltQueueEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+		url, depth, ok = e.URL, e.Depth, true
+		return nil
+	})
+	if err != nil {
+		logrus.Warnf("bolt store: Dequeue failed: %+v", err)
+		return "", 0, false
+	}
+	return url, depth, ok
+}
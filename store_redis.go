@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// RedisStore is a Store backed by Redis, letting a crawl resume from
+// any process that can reach the same Redis instance, or be shared
+// across several crawler processes.
+type RedisStore struct {
+	Client *redis.Client
+	// Namespace scopes the seen set and frontier key for this crawl,
+	// so one Redis instance can host several crawls' state at once.
+	Namespace string
+}
+
+// NewRedisStore builds a RedisStore scoped to namespace on the given
+// Redis client.
+func NewRedisStore(client *redis.Client, namespace string) *RedisStore {
+	return &RedisStore{Client: client, Namespace: namespace}
+}
+
+func (s *RedisStore) seenKey() string  { return "crawler:" + s.Namespace + ":seen" }
+func (s *RedisStore) queueKey() string { return "crawler:" + s.Namespace + ":queue" }
+
+func (s *RedisStore) Seen(url string) bool {
+	seen, err := s.Client.SIsMember(context.Background(), s.seenKey(), url).Result()
+	if err != nil {
+		logrus.WithField("url", url).Warnf("redis store: Seen failed: %+v", err)
+		return false
+	}
+	return seen
+}
+
+func (s *RedisStore) Mark(url string) {
+	if err := s.Client.SAdd(context.Background(), s.seenKey(), url).Err(); err != nil {
+		logrus.WithField("url", url).Warnf("redis store: Mark failed: %+v", err)
+	}
+}
+
+// TryMark relies on SADD's return value (the number of members actually
+// added) to check and set url as seen in one atomic round trip.
+func (s *RedisStore) TryMark(url string) bool {
+	n, err := s.Client.SAdd(context.Background(), s.seenKey(), url).Result()
+	if err != nil {
+		logrus.WithField("url", url).Warnf("redis store: TryMark failed: %+v", err)
+		return false
+	}
+	return n > 0
+}
+
+type redisQueueEntry struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+func (s *RedisStore) Enqueue(url string, depth int) {
+	b, err := json.Marshal(redisQueueEntry{URL: url, Depth: depth})
+	if err != nil {
+		logrus.WithField("url", url).Warnf("redis store: Enqueue marshal failed: %+v", err)
+		return
+	}
+	if err := s.Client.RPush(context.Background(), s.queueKey(), b).Err(); err != nil {
+		logrus.WithField("url", url).Warnf("redis store: Enqueue failed: %+v", err)
+	}
+}
+
+func (s *RedisStore) Dequeue() (string, int, bool) {
+	v, err := s.Client.LPop(context.Background(), s.queueKey()).Result()
+	if err == redis.Nil {
+		return "", 0, false
+	}
+	if err != nil {
+		logrus.Warnf("redis store: Dequeue failed: %+v", err)
+		return "", 0, false
+	}
+	var e redisQueueEntry
+	if err := json.Unmarshal([]byte(v), &e); err != nil {
+		logrus.Warnf("redis store: Dequeue unmarshal failed: %+v", err)
+		return "", 0, false
+	}
+	return e.URL, e.Depth, true
+}
@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilterNoFalseNegatives(t *testing.T) {
+	b := newBloomFilter(10_000, 4)
+	added := make([]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		s := fmt.Sprintf("https://example.com/page/%d", i)
+		b.add(s)
+		added = append(added, s)
+	}
+	for _, s := range added {
+		if !b.mightContain(s) {
+			t.Fatalf("mightContain(%q) = false after add, bloom filters must never false-negative", s)
+		}
+	}
+}
+
+func TestBloomFilterFalsePositiveRateIsBounded(t *testing.T) {
+	// ~1% is the rate NewMemoryStore's doc comment promises at roughly a
+	// million URLs with 10M bits; check the same ratio holds at smaller
+	// scale so a regression in the hashing or bit math shows up here
+	// instead of in a million-URL crawl.
+	const m, k, n = 100_000, 4, 10_000
+	b := newBloomFilter(m, k)
+	for i := 0; i < n; i++ {
+		b.add(fmt.Sprintf("seen-%d", i))
+	}
+	falsePositives := 0
+	const trials = 10_000
+	for i := 0; i < trials; i++ {
+		if b.mightContain(fmt.Sprintf("unseen-%d", i)) {
+			falsePositives++
+		}
+	}
+	rate := float64(falsePositives) / trials
+	if rate > 0.05 {
+		t.Fatalf("false-positive rate = %.4f, want <= 0.05 for m=%d k=%d n=%d", rate, m, k, n)
+	}
+}
+
+func TestMemoryStoreTryMarkIsCheckAndSet(t *testing.T) {
+	s := NewMemoryStore()
+	if !s.TryMark("https://example.com/a") {
+		t.Fatal("first TryMark of a fresh URL should return true")
+	}
+	if s.TryMark("https://example.com/a") {
+		t.Fatal("second TryMark of the same URL should return false")
+	}
+	if !s.Seen("https://example.com/a") {
+		t.Fatal("Seen should report true once TryMark has marked a URL")
+	}
+}
+
+func TestMemoryStoreTryMarkConcurrent(t *testing.T) {
+	s := NewMemoryStore()
+	const n = 200
+	wins := make(chan bool, n)
+	for i := 0; i < n; i++ {
+		go func() { wins <- s.TryMark("https://example.com/shared") }()
+	}
+	trueCount := 0
+	for i := 0; i < n; i++ {
+		if <-wins {
+			trueCount++
+		}
+	}
+	if trueCount != 1 {
+		t.Fatalf("exactly one concurrent TryMark should win, got %d", trueCount)
+	}
+}
+
+func TestMemoryStoreEnqueueDequeueFIFO(t *testing.T) {
+	s := NewMemoryStore()
+	s.Enqueue("https://example.com/a", 0)
+	s.Enqueue("https://example.com/b", 1)
+
+	u, depth, ok := s.Dequeue()
+	if !ok || u != "https://example.com/a" || depth != 0 {
+		t.Fatalf("Dequeue() = %q, %d, %v, want a, 0, true", u, depth, ok)
+	}
+	u, depth, ok = s.Dequeue()
+	if !ok || u != "https://example.com/b" || depth != 1 {
+		t.Fatalf("Dequeue() = %q, %d, %v, want b, 1, true", u, depth, ok)
+	}
+	if _, _, ok := s.Dequeue(); ok {
+		t.Fatal("Dequeue() on an empty store should report ok == false")
+	}
+}
+
+func TestMemoryStoreSeenFallsBackToBloomPastExactCap(t *testing.T) {
+	s := NewMemoryStoreSized(10_000, 2)
+	s.Mark("https://example.com/a")
+	s.Mark("https://example.com/b")
+	// The exact set is now full; a third Mark should still record the
+	// URL in the bloom filter even though it's not tracked exactly.
+	s.Mark("https://example.com/c")
+	if !s.Seen("https://example.com/c") {
+		t.Fatal("Seen should trust the bloom filter once the exact set is at capacity")
+	}
+}